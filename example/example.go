@@ -26,16 +26,17 @@ func main() {
 			log.Printf("%s STATE is Started", tun.Name())
 		case sshtun.StateStopped:
 			log.Printf("%s STATE is Stopped", tun.Name())
+		case sshtun.StateReconnecting:
+			log.Printf("%s STATE is Reconnecting", tun.Name())
 		}
 	})
 
-	// We start the tunnel (and restart it every time it is stopped)
+	// We start the tunnel and let Run restart it every time it is stopped,
+	// backing off by a second between attempts so a start error doesn't flood.
+	sshTun.SetReconnectPolicy(sshtun.ReconnectPolicy{InitialBackoff: time.Second})
 	go func() {
-		for {
-			if err := sshTun.Start(context.Background()); err != nil {
-				log.Printf("SSH tunnel error: %v", err)
-				time.Sleep(time.Second) // don't flood if there's a start error :)
-			}
+		if err := sshTun.Run(context.Background()); err != nil {
+			log.Printf("SSH tunnel error: %v", err)
 		}
 	}()
 
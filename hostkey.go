@@ -0,0 +1,138 @@
+package sshtun
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// ErrUnknownHost is returned by the host key callback when the server offers a
+// key that isn't present in the known_hosts file and TOFU isn't enabled.
+type ErrUnknownHost struct {
+	Hostname    string
+	Fingerprint string
+}
+
+func (e *ErrUnknownHost) Error() string {
+	return fmt.Sprintf("unknown host %s, offered key fingerprint %s", e.Hostname, e.Fingerprint)
+}
+
+// ErrHostKeyChanged is returned by the host key callback when the server offers
+// a key that differs from the one recorded for it in the known_hosts file.
+type ErrHostKeyChanged struct {
+	Hostname    string
+	Fingerprint string
+}
+
+func (e *ErrHostKeyChanged) Error() string {
+	return fmt.Sprintf("host key for %s changed, offered fingerprint %s", e.Hostname, e.Fingerprint)
+}
+
+// SetKnownHostsFile changes the known_hosts file used to verify the server's host
+// key. Leaving it empty (the default) uses `~/.ssh/known_hosts`.
+func (tun *SSHTun) SetKnownHostsFile(path string) {
+	tun.knownHostsFile = path
+}
+
+// SetKnownHostsTOFU enables trust-on-first-use: a host that isn't yet present in
+// the known_hosts file has its key appended instead of the connection being
+// rejected with ErrUnknownHost. Keys that change after being recorded this way
+// still fail with ErrHostKeyChanged.
+func (tun *SSHTun) SetKnownHostsTOFU(tofu bool) {
+	tun.knownHostsTOFU = tofu
+}
+
+// SetHostKeyCallback overrides host key verification with a custom callback,
+// bypassing the known_hosts file entirely.
+func (tun *SSHTun) SetHostKeyCallback(callback ssh.HostKeyCallback) {
+	tun.hostKeyCallback = callback
+}
+
+// SetHostKeyAlgorithms restricts the host key algorithms the server may offer.
+// Supported algorithms are listed at https://pkg.go.dev/golang.org/x/crypto/ssh#Config
+func (tun *SSHTun) SetHostKeyAlgorithms(algorithms []string) {
+	tun.hostKeyAlgorithms = algorithms
+}
+
+// SetInsecureIgnoreHostKey disables host key verification entirely, restoring
+// the library's old behavior. This is insecure and should only be used as an
+// explicit, deliberate opt-out.
+func (tun *SSHTun) SetInsecureIgnoreHostKey() {
+	tun.insecureIgnoreHostKey = true
+}
+
+// buildHostKeyCallback resolves the ssh.HostKeyCallback to use for this tunnel:
+// a custom callback if one was set, InsecureIgnoreHostKey if that opt-out was
+// requested, or known_hosts-backed verification against tun.knownHostsFile
+// (defaulting to ~/.ssh/known_hosts), honoring SetKnownHostsTOFU.
+func (tun *SSHTun) buildHostKeyCallback() (ssh.HostKeyCallback, error) {
+	if tun.hostKeyCallback != nil {
+		return tun.hostKeyCallback, nil
+	}
+
+	if tun.insecureIgnoreHostKey {
+		return ssh.InsecureIgnoreHostKey(), nil
+	}
+
+	path := tun.knownHostsFile
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("resolve default known_hosts file failed: %w", err)
+		}
+		path = filepath.Join(home, ".ssh", "known_hosts")
+	}
+
+	dbCallback, err := knownhosts.New(path)
+	if err != nil {
+		if !tun.knownHostsTOFU || !os.IsNotExist(err) {
+			return nil, fmt.Errorf("load known_hosts file %s failed: %w", path, err)
+		}
+		dbCallback = func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			return &knownhosts.KeyError{}
+		}
+	}
+
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		err := dbCallback(hostname, remote, key)
+		if err == nil {
+			return nil
+		}
+
+		var keyErr *knownhosts.KeyError
+		if !errors.As(err, &keyErr) {
+			return err
+		}
+
+		if len(keyErr.Want) > 0 {
+			return &ErrHostKeyChanged{Hostname: hostname, Fingerprint: ssh.FingerprintSHA256(key)}
+		}
+
+		if tun.knownHostsTOFU {
+			return appendKnownHost(path, hostname, key)
+		}
+
+		return &ErrUnknownHost{Hostname: hostname, Fingerprint: ssh.FingerprintSHA256(key)}
+	}, nil
+}
+
+// appendKnownHost records a first-seen host key, creating the known_hosts file
+// if it doesn't exist yet.
+func appendKnownHost(path string, hostname string, key ssh.PublicKey) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("open known_hosts file %s failed: %w", path, err)
+	}
+	defer f.Close()
+
+	if _, err := f.WriteString(knownhosts.Line([]string{hostname}, key) + "\n"); err != nil {
+		return fmt.Errorf("write known_hosts file %s failed: %w", path, err)
+	}
+
+	return nil
+}
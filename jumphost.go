@@ -0,0 +1,131 @@
+package sshtun
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// JumpHost describes one bastion hop in a ProxyJump chain. It carries its own
+// endpoint and authentication settings, configured the same way as an SSHTun's
+// (SetUser, SetPassword, SetKeyFile, SetKnownHostsFile, ...); only its
+// connection-related fields are used when chaining, so its forward-related
+// methods have no effect.
+type JumpHost struct {
+	*SSHTun
+}
+
+// NewJumpHost creates a bastion hop connecting to server on port 22 as root,
+// mirroring the defaults of New.
+func NewJumpHost(server string) *JumpHost {
+	return &JumpHost{SSHTun: defaultSSHTun(server)}
+}
+
+// SetJumpHosts chains the tunnel's SSH connection through one or more bastions,
+// in order: the first jump is dialed directly, each subsequent hop (and finally
+// tun.server) is dialed through the previous one via its *ssh.Client. Host key
+// verification applies at every hop, using each JumpHost's own settings.
+func (tun *SSHTun) SetJumpHosts(jumps ...*JumpHost) {
+	tun.jumpHosts = jumps
+}
+
+// dialServer establishes the *ssh.Client used for this tunnel's forwards,
+// chaining through any configured jump hosts before reaching tun.server.
+func (tun *SSHTun) dialServer() (*ssh.Client, error) {
+	if len(tun.jumpHosts) == 0 {
+		client, err := ssh.Dial(tun.server.Type(), tun.server.String(), tun.sshConfig)
+		if err != nil {
+			return nil, fmt.Errorf("ssh dial %s to %s failed: %w", tun.server.Type(), tun.server.String(), err)
+		}
+		return client, nil
+	}
+
+	var client *ssh.Client
+	for i, jump := range tun.jumpHosts {
+		config, err := jump.initSSHConfig()
+		if err != nil {
+			if client != nil {
+				client.Close()
+			}
+			return nil, fmt.Errorf("jump host %d (%s) ssh config failed: %w", i, jump.server.String(), err)
+		}
+
+		var next *ssh.Client
+		if client == nil {
+			next, err = ssh.Dial(jump.server.Type(), jump.server.String(), config)
+		} else {
+			next, err = dialNextHop(client, jump.server.Type(), jump.server.String(), config)
+		}
+		if err != nil {
+			if client != nil {
+				client.Close()
+			}
+			return nil, fmt.Errorf("jump host %d (%s) dial failed: %w", i, jump.server.String(), err)
+		}
+		client = next
+	}
+
+	final, err := dialNextHop(client, tun.server.Type(), tun.server.String(), tun.sshConfig)
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("ssh dial %s to %s through jump hosts failed: %w", tun.server.Type(), tun.server.String(), err)
+	}
+
+	return final, nil
+}
+
+// parseProxyJumpSpec turns an OpenSSH ProxyJump directive value
+// ("[user@]host[:port][,[user@]host[:port]...]") into the equivalent chain of
+// jump hosts, defaulting each hop's port to 22.
+func parseProxyJumpSpec(spec string) []*JumpHost {
+	var jumps []*JumpHost
+
+	for _, hop := range strings.Split(spec, ",") {
+		hop = strings.TrimSpace(hop)
+		if hop == "" {
+			continue
+		}
+
+		user, hostPort := "", hop
+		if idx := strings.Index(hop, "@"); idx >= 0 {
+			user, hostPort = hop[:idx], hop[idx+1:]
+		}
+
+		host, port := hostPort, 22
+		if h, p, err := net.SplitHostPort(hostPort); err == nil {
+			host = h
+			if n, err := strconv.Atoi(p); err == nil {
+				port = n
+			}
+		}
+
+		jump := NewJumpHost(host)
+		jump.SetPort(port)
+		// NewJumpHost defaults to "root" like any other SSHTun; a ProxyJump hop
+		// with no "user@" prefix has no such default, so clear it back out.
+		jump.user = user
+		jumps = append(jumps, jump)
+	}
+
+	return jumps
+}
+
+// dialNextHop opens a net.Conn to addr through an already-established client and
+// layers a new *ssh.Client on top of it, continuing a ProxyJump chain.
+func dialNextHop(client *ssh.Client, network, addr string, config *ssh.ClientConfig) (*ssh.Client, error) {
+	conn, err := client.Dial(network, addr)
+	if err != nil {
+		return nil, fmt.Errorf("dial %s on %s failed: %w", network, addr, err)
+	}
+
+	clientConn, chans, reqs, err := ssh.NewClientConn(conn, addr, config)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("ssh handshake with %s failed: %w", addr, err)
+	}
+
+	return ssh.NewClient(clientConn, chans, reqs), nil
+}
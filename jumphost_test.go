@@ -0,0 +1,45 @@
+package sshtun
+
+import "testing"
+
+func TestParseProxyJumpSpec(t *testing.T) {
+	tests := []struct {
+		name      string
+		spec      string
+		wantHosts []string
+		wantPorts []int
+		wantUsers []string
+	}{
+		{"empty spec", "", nil, nil, nil},
+		{"single host default port", "bastion.example.com", []string{"bastion.example.com"}, []int{22}, []string{""}},
+		{"single host with user and port", "alice@bastion.example.com:2222", []string{"bastion.example.com"}, []int{2222}, []string{"alice"}},
+		{
+			"multi-hop chain",
+			"alice@b1.example.com:2222,b2.example.com",
+			[]string{"b1.example.com", "b2.example.com"},
+			[]int{2222, 22},
+			[]string{"alice", ""},
+		},
+		{"blank hops are skipped", " , bastion.example.com ,", []string{"bastion.example.com"}, []int{22}, []string{""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jumps := parseProxyJumpSpec(tt.spec)
+			if len(jumps) != len(tt.wantHosts) {
+				t.Fatalf("parseProxyJumpSpec(%q) returned %d jumps, want %d", tt.spec, len(jumps), len(tt.wantHosts))
+			}
+			for i, jump := range jumps {
+				if jump.server.host != tt.wantHosts[i] {
+					t.Errorf("jump %d host = %q, want %q", i, jump.server.host, tt.wantHosts[i])
+				}
+				if jump.server.port != tt.wantPorts[i] {
+					t.Errorf("jump %d port = %d, want %d", i, jump.server.port, tt.wantPorts[i])
+				}
+				if jump.user != tt.wantUsers[i] {
+					t.Errorf("jump %d user = %q, want %q", i, jump.user, tt.wantUsers[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package sshtun
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// SetKeepAlive makes the tunnel send an SSH keepalive request over its shared
+// client every interval once that client is established. After maxMisses
+// consecutive requests fail or go unanswered, the tunnel's context is
+// cancelled, making Start return an error so a caller's restart loop (or Run)
+// can re-dial.
+func (tun *SSHTun) SetKeepAlive(interval time.Duration, maxMisses int) {
+	tun.keepAliveInterval = interval
+	tun.keepAliveMaxMisses = maxMisses
+}
+
+// startKeepAlive spawns the keepalive loop for sshClient if SetKeepAlive was
+// called; it's a no-op otherwise.
+func (tun *SSHTun) startKeepAlive(sshClient *ssh.Client) {
+	if tun.keepAliveInterval <= 0 {
+		return
+	}
+	go tun.keepAlive(sshClient)
+}
+
+// keepAlive sends "keepalive@openssh.com" over sshClient every
+// tun.keepAliveInterval until the tunnel's context is cancelled or it misses
+// tun.keepAliveMaxMisses replies in a row, at which point it cancels the
+// tunnel itself so Start returns and a caller can reconnect.
+func (tun *SSHTun) keepAlive(sshClient *ssh.Client) {
+	ticker := time.NewTicker(tun.keepAliveInterval)
+	defer ticker.Stop()
+
+	misses := 0
+	for {
+		select {
+		case <-tun.ctx.Done():
+			return
+		case <-ticker.C:
+			replyChan := make(chan error, 1)
+			go func() {
+				_, _, err := sshClient.SendRequest("keepalive@openssh.com", true, nil)
+				replyChan <- err
+			}()
+			var err error
+			select {
+			case err = <-replyChan:
+			case <-time.After(tun.keepAliveInterval):
+				err = fmt.Errorf("keepalive: no reply within %s", tun.keepAliveInterval)
+			case <-tun.ctx.Done():
+				return
+			}
+			if err != nil {
+				misses++
+			} else {
+				misses = 0
+			}
+			if tun.keepAliveMaxMisses > 0 && misses >= tun.keepAliveMaxMisses {
+				tun.mutex.Lock()
+				tun.keepAliveErr = fmt.Errorf("keepalive: missed %d replies in a row", misses)
+				tun.mutex.Unlock()
+				tun.cancel()
+				return
+			}
+		}
+	}
+}
+
+// ReconnectPolicy configures the backoff Run uses between automatic restarts.
+type ReconnectPolicy struct {
+	// InitialBackoff is the delay before the first restart attempt.
+	InitialBackoff time.Duration
+	// MaxBackoff caps how large the delay can grow. Zero means unbounded.
+	MaxBackoff time.Duration
+	// Multiplier scales the delay after each attempt. Values <= 1 keep it constant.
+	Multiplier float64
+	// Jitter adds up to Jitter*delay of random extra wait, to avoid restart storms.
+	Jitter float64
+	// MaxAttempts caps how many times Run will start the tunnel, the first start
+	// included. Zero means unlimited.
+	MaxAttempts int
+}
+
+// SetReconnectPolicy sets the backoff Run uses between automatic restarts.
+// Without it, Run waits a constant second between attempts, with no attempt limit.
+func (tun *SSHTun) SetReconnectPolicy(policy ReconnectPolicy) {
+	tun.reconnectPolicy = &policy
+}
+
+// Run starts the tunnel and keeps restarting it whenever it stops (Stop, an
+// error, or a keepalive timing out), backing off between attempts per
+// SetReconnectPolicy, until ctx is cancelled or the policy's MaxAttempts is
+// reached. It replaces the hand-rolled `for { Start(); backoff }` loop a
+// caller would otherwise write themselves.
+func (tun *SSHTun) Run(ctx context.Context) error {
+	policy := tun.reconnectPolicy
+	if policy == nil {
+		policy = &ReconnectPolicy{InitialBackoff: time.Second}
+	}
+
+	backoff := policy.InitialBackoff
+	attempts := 0
+
+	for {
+		err := tun.Start(ctx)
+
+		select {
+		case <-ctx.Done():
+			return err
+		default:
+		}
+
+		attempts++
+		if policy.MaxAttempts > 0 && attempts >= policy.MaxAttempts {
+			return err
+		}
+
+		if tun.connState != nil {
+			tun.connState(tun, StateReconnecting)
+		}
+
+		wait := backoff
+		if policy.Jitter > 0 {
+			wait += time.Duration(policy.Jitter * float64(backoff) * rand.Float64())
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(wait):
+		}
+
+		if policy.Multiplier > 1 {
+			backoff = time.Duration(float64(backoff) * policy.Multiplier)
+			if policy.MaxBackoff > 0 && backoff > policy.MaxBackoff {
+				backoff = policy.MaxBackoff
+			}
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package sshtun
+
+import (
+	"context"
+	"sync"
+)
+
+// TunnelManager owns a set of named tunnels, letting a caller Add, Remove,
+// Get, List, StartAll and StopAll them together instead of wiring each
+// *SSHTun's lifecycle up by hand. Every tunnel Add'd to it reports its state
+// changes through a single shared event bus (see Subscribe), and optionally to
+// a MetricsSink (see SetMetricsSink).
+type TunnelManager struct {
+	mutex       sync.Mutex
+	tunnels     map[string]*SSHTun
+	activeConns map[string]int
+	metrics     MetricsSink
+	subscribers []func(name string, tun *SSHTun, state ConnState)
+}
+
+// NewTunnelManager creates an empty TunnelManager.
+func NewTunnelManager() *TunnelManager {
+	return &TunnelManager{
+		tunnels:     make(map[string]*SSHTun),
+		activeConns: make(map[string]int),
+	}
+}
+
+// SetMetricsSink wires up a MetricsSink (such as NewPrometheusCollector's
+// result) that every tunnel Add'd to this manager reports to.
+func (m *TunnelManager) SetMetricsSink(sink MetricsSink) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.metrics = sink
+}
+
+// Subscribe registers a callback invoked whenever any tunnel owned by this
+// manager changes state, alongside whatever SetConnState callback it already has.
+func (m *TunnelManager) Subscribe(fn func(name string, tun *SSHTun, state ConnState)) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.subscribers = append(m.subscribers, fn)
+}
+
+// Add registers tun under name and instruments its state callbacks to also
+// feed this manager's event bus and metrics sink. Call tun.SetConnState or
+// tun.SetTunneledConnState again after Add and the manager's wrapping is
+// discarded along with it; use Subscribe instead to add callbacks once a
+// tunnel is managed.
+func (m *TunnelManager) Add(name string, tun *SSHTun) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.tunnels[name] = tun
+	m.instrument(name, tun)
+}
+
+// Remove stops and unregisters the tunnel named name, if any.
+func (m *TunnelManager) Remove(name string) {
+	m.mutex.Lock()
+	tun, ok := m.tunnels[name]
+	delete(m.tunnels, name)
+	delete(m.activeConns, name)
+	m.mutex.Unlock()
+
+	if ok {
+		tun.Stop()
+	}
+}
+
+// Get returns the tunnel named name, or nil if none is registered.
+func (m *TunnelManager) Get(name string) *SSHTun {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.tunnels[name]
+}
+
+// List returns the names of every tunnel currently registered.
+func (m *TunnelManager) List() []string {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	names := make([]string, 0, len(m.tunnels))
+	for name := range m.tunnels {
+		names = append(names, name)
+	}
+	return names
+}
+
+// StartAll starts every registered tunnel concurrently, returning once they've
+// all stopped or ctx is cancelled. The result maps each tunnel's name to the
+// error Start returned for it.
+func (m *TunnelManager) StartAll(ctx context.Context) map[string]error {
+	m.mutex.Lock()
+	tunnels := make(map[string]*SSHTun, len(m.tunnels))
+	for name, tun := range m.tunnels {
+		tunnels[name] = tun
+	}
+	m.mutex.Unlock()
+
+	var (
+		wg        sync.WaitGroup
+		errsMutex sync.Mutex
+	)
+	errs := make(map[string]error, len(tunnels))
+
+	for name, tun := range tunnels {
+		name, tun := name, tun
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			err := tun.Start(ctx)
+
+			m.mutex.Lock()
+			sink := m.metrics
+			m.mutex.Unlock()
+			if err != nil && sink != nil {
+				sink.IncOpenFailures(name)
+				sink.SetLastError(name, err)
+			}
+
+			errsMutex.Lock()
+			errs[name] = err
+			errsMutex.Unlock()
+		}()
+	}
+
+	wg.Wait()
+
+	return errs
+}
+
+// StopAll stops every registered tunnel.
+func (m *TunnelManager) StopAll() {
+	m.mutex.Lock()
+	tunnels := make([]*SSHTun, 0, len(m.tunnels))
+	for _, tun := range m.tunnels {
+		tunnels = append(tunnels, tun)
+	}
+	m.mutex.Unlock()
+
+	for _, tun := range tunnels {
+		tun.Stop()
+	}
+}
+
+// instrument wraps tun's SetConnState/SetTunneledConnState callbacks so state
+// changes and connection byte counts also reach this manager's event bus and
+// metrics sink, without discarding whatever callback tun already had set.
+func (m *TunnelManager) instrument(name string, tun *SSHTun) {
+	prevConnState := tun.connState
+	tun.SetConnState(func(t *SSHTun, state ConnState) {
+		if prevConnState != nil {
+			prevConnState(t, state)
+		}
+		m.onConnState(name, t, state)
+	})
+
+	prevTunneledConnState := tun.tunneledConnState
+	tun.SetTunneledConnState(func(t *SSHTun, state *TunneledConnState) {
+		if prevTunneledConnState != nil {
+			prevTunneledConnState(t, state)
+		}
+		m.onTunneledConnState(name, state)
+	})
+}
+
+func (m *TunnelManager) onConnState(name string, tun *SSHTun, state ConnState) {
+	m.mutex.Lock()
+	sink := m.metrics
+	subscribers := append([]func(string, *SSHTun, ConnState){}, m.subscribers...)
+	m.mutex.Unlock()
+
+	if sink != nil {
+		switch state {
+		case StateStarting:
+			sink.IncOpenTotal(name)
+		case StateStarted:
+			sink.SetUp(name, true)
+		case StateStopped:
+			sink.SetUp(name, false)
+		}
+	}
+
+	for _, fn := range subscribers {
+		fn(name, tun, state)
+	}
+}
+
+func (m *TunnelManager) onTunneledConnState(name string, state *TunneledConnState) {
+	m.mutex.Lock()
+	if _, ok := m.tunnels[name]; !ok {
+		// Removed since this connection was accepted; don't resurrect its
+		// activeConns entry for whatever tunnel might reuse name next.
+		m.mutex.Unlock()
+		return
+	}
+	sink := m.metrics
+	if state.Closed {
+		m.activeConns[name]--
+	} else {
+		m.activeConns[name]++
+	}
+	active := m.activeConns[name]
+	m.mutex.Unlock()
+
+	if sink == nil {
+		return
+	}
+
+	sink.SetActiveConnections(name, active)
+
+	if !state.Closed {
+		return
+	}
+	if state.BytesIn > 0 {
+		sink.AddBytesTransferred(name, "in", state.BytesIn)
+	}
+	if state.BytesOut > 0 {
+		sink.AddBytesTransferred(name, "out", state.BytesOut)
+	}
+}
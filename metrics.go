@@ -0,0 +1,21 @@
+package sshtun
+
+// MetricsSink receives the metrics a TunnelManager collects about the tunnels
+// it owns. Implementations can feed any exporter (Prometheus, OpenTelemetry,
+// a plain log line, ...) without this package depending on one directly; see
+// NewPrometheusCollector in the prometheus build-tagged file for a ready-made
+// Prometheus implementation.
+type MetricsSink interface {
+	// IncOpenTotal counts one Start attempt for the named tunnel.
+	IncOpenTotal(name string)
+	// IncOpenFailures counts one failed Start attempt for the named tunnel.
+	IncOpenFailures(name string)
+	// SetActiveConnections reports the named tunnel's current tunneled connection count.
+	SetActiveConnections(name string, n int)
+	// AddBytesTransferred adds n bytes transferred by the named tunnel in direction dir ("in" or "out").
+	AddBytesTransferred(name, dir string, n int64)
+	// SetLastError records that the named tunnel just failed with err.
+	SetLastError(name string, err error)
+	// SetUp reports whether the named tunnel is currently up.
+	SetUp(name string, up bool)
+}
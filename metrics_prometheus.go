@@ -0,0 +1,101 @@
+//go:build prometheus
+
+package sshtun
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// PrometheusCollector implements both MetricsSink and prometheus.Collector, so
+// it can be fed to a TunnelManager with SetMetricsSink and registered on a
+// caller-owned registry with reg.MustRegister(collector) - no package-level
+// registry and no hard dependency on this file for callers who'd rather plug
+// in another exporter (it only builds with the "prometheus" tag).
+type PrometheusCollector struct {
+	openTotal          *prometheus.CounterVec
+	openFailuresTotal  *prometheus.CounterVec
+	activeConnections  *prometheus.GaugeVec
+	bytesTransferred   *prometheus.CounterVec
+	lastErrorTimestamp *prometheus.GaugeVec
+	up                 *prometheus.GaugeVec
+}
+
+// NewPrometheusCollector creates a PrometheusCollector exposing
+// sshtun_open_total, sshtun_open_failures_total, sshtun_active_connections,
+// sshtun_bytes_transferred, sshtun_last_error_timestamp and sshtun_up.
+func NewPrometheusCollector() *PrometheusCollector {
+	return &PrometheusCollector{
+		openTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "sshtun_open_total",
+			Help: "Total number of times a tunnel was started.",
+		}, []string{"name"}),
+		openFailuresTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "sshtun_open_failures_total",
+			Help: "Total number of times starting a tunnel failed.",
+		}, []string{"name"}),
+		activeConnections: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "sshtun_active_connections",
+			Help: "Number of connections currently being tunneled.",
+		}, []string{"name"}),
+		bytesTransferred: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "sshtun_bytes_transferred",
+			Help: "Total bytes transferred through a tunnel.",
+		}, []string{"name", "dir"}),
+		lastErrorTimestamp: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "sshtun_last_error_timestamp",
+			Help: "Unix timestamp of the last error for a tunnel.",
+		}, []string{"name"}),
+		up: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "sshtun_up",
+			Help: "Whether a tunnel is currently up (1) or not (0).",
+		}, []string{"name"}),
+	}
+}
+
+func (c *PrometheusCollector) IncOpenTotal(name string) {
+	c.openTotal.WithLabelValues(name).Inc()
+}
+
+func (c *PrometheusCollector) IncOpenFailures(name string) {
+	c.openFailuresTotal.WithLabelValues(name).Inc()
+}
+
+func (c *PrometheusCollector) SetActiveConnections(name string, n int) {
+	c.activeConnections.WithLabelValues(name).Set(float64(n))
+}
+
+func (c *PrometheusCollector) AddBytesTransferred(name, dir string, n int64) {
+	c.bytesTransferred.WithLabelValues(name, dir).Add(float64(n))
+}
+
+func (c *PrometheusCollector) SetLastError(name string, err error) {
+	c.lastErrorTimestamp.WithLabelValues(name).SetToCurrentTime()
+}
+
+func (c *PrometheusCollector) SetUp(name string, up bool) {
+	value := 0.0
+	if up {
+		value = 1.0
+	}
+	c.up.WithLabelValues(name).Set(value)
+}
+
+// Describe implements prometheus.Collector.
+func (c *PrometheusCollector) Describe(ch chan<- *prometheus.Desc) {
+	c.openTotal.Describe(ch)
+	c.openFailuresTotal.Describe(ch)
+	c.activeConnections.Describe(ch)
+	c.bytesTransferred.Describe(ch)
+	c.lastErrorTimestamp.Describe(ch)
+	c.up.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (c *PrometheusCollector) Collect(ch chan<- prometheus.Metric) {
+	c.openTotal.Collect(ch)
+	c.openFailuresTotal.Collect(ch)
+	c.activeConnections.Collect(ch)
+	c.bytesTransferred.Collect(ch)
+	c.lastErrorTimestamp.Collect(ch)
+	c.up.Collect(ch)
+}
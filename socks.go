@@ -0,0 +1,135 @@
+package sshtun
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+)
+
+// SOCKS5 protocol constants used by the Dynamic forward handshake, as defined in RFC 1928.
+const (
+	socks5Version = 0x05
+
+	socks5AuthNone = 0x00
+
+	socks5CmdConnect      = 0x01
+	socks5CmdBind         = 0x02
+	socks5CmdUDPAssociate = 0x03
+
+	socks5AddrIPv4   = 0x01
+	socks5AddrDomain = 0x03
+	socks5AddrIPv6   = 0x04
+
+	socks5ReplySuccess             = 0x00
+	socks5ReplyHostUnreachable     = 0x04
+	socks5ReplyCommandNotSupported = 0x07
+)
+
+// NewDynamic creates a new SSH tunnel that turns localPort into a SOCKS5 proxy
+// (like `ssh -D`), letting a browser or HTTP client tunnel arbitrary destinations
+// through the SSH connection to server instead of a fixed remote host/port. It's
+// a thin convenience over AddTunnel with a Dynamic TunnelSpec, which does the
+// actual listening, dialing and bridging.
+func NewDynamic(localPort int, server string) *SSHTun {
+	sshTun := defaultSSHTun(server)
+	sshTun.AddTunnel(TunnelSpec{
+		Direction:  Dynamic,
+		ListenNet:  "tcp",
+		ListenAddr: net.JoinHostPort("localhost", strconv.Itoa(localPort)),
+	})
+	return sshTun
+}
+
+// socks5Handshake reads the SOCKS5 version/method greeting and a CONNECT
+// request off conn, replying with the no-auth method and returning, for CONNECT,
+// the requested "host:port" target. BIND and UDP ASSOCIATE are rejected with
+// "command not supported".
+func socks5Handshake(conn net.Conn) (string, error) {
+	r := bufio.NewReader(conn)
+
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return "", fmt.Errorf("read greeting failed: %w", err)
+	}
+	if header[0] != socks5Version {
+		return "", fmt.Errorf("unsupported SOCKS version %d", header[0])
+	}
+
+	methods := make([]byte, header[1])
+	if _, err := io.ReadFull(r, methods); err != nil {
+		return "", fmt.Errorf("read auth methods failed: %w", err)
+	}
+
+	if _, err := conn.Write([]byte{socks5Version, socks5AuthNone}); err != nil {
+		return "", fmt.Errorf("write method selection failed: %w", err)
+	}
+
+	request := make([]byte, 4)
+	if _, err := io.ReadFull(r, request); err != nil {
+		return "", fmt.Errorf("read request failed: %w", err)
+	}
+	if request[0] != socks5Version {
+		return "", fmt.Errorf("unsupported SOCKS version %d", request[0])
+	}
+
+	cmd := request[1]
+	if cmd == socks5CmdBind || cmd == socks5CmdUDPAssociate {
+		socks5WriteReply(conn, socks5ReplyCommandNotSupported)
+		return "", fmt.Errorf("command %d not supported", cmd)
+	}
+	if cmd != socks5CmdConnect {
+		socks5WriteReply(conn, socks5ReplyCommandNotSupported)
+		return "", fmt.Errorf("command %d not supported", cmd)
+	}
+
+	host, err := socks5ReadAddr(r, request[3])
+	if err != nil {
+		return "", err
+	}
+
+	portBytes := make([]byte, 2)
+	if _, err := io.ReadFull(r, portBytes); err != nil {
+		return "", fmt.Errorf("read port failed: %w", err)
+	}
+	port := int(portBytes[0])<<8 | int(portBytes[1])
+
+	return net.JoinHostPort(host, strconv.Itoa(port)), nil
+}
+
+func socks5ReadAddr(r *bufio.Reader, addrType byte) (string, error) {
+	switch addrType {
+	case socks5AddrIPv4:
+		addr := make([]byte, 4)
+		if _, err := io.ReadFull(r, addr); err != nil {
+			return "", fmt.Errorf("read IPv4 address failed: %w", err)
+		}
+		return net.IP(addr).String(), nil
+	case socks5AddrIPv6:
+		addr := make([]byte, 16)
+		if _, err := io.ReadFull(r, addr); err != nil {
+			return "", fmt.Errorf("read IPv6 address failed: %w", err)
+		}
+		return net.IP(addr).String(), nil
+	case socks5AddrDomain:
+		length := make([]byte, 1)
+		if _, err := io.ReadFull(r, length); err != nil {
+			return "", fmt.Errorf("read domain length failed: %w", err)
+		}
+		domain := make([]byte, length[0])
+		if _, err := io.ReadFull(r, domain); err != nil {
+			return "", fmt.Errorf("read domain failed: %w", err)
+		}
+		return string(domain), nil
+	default:
+		return "", fmt.Errorf("unsupported address type %d", addrType)
+	}
+}
+
+// socks5WriteReply writes a CONNECT reply with a zeroed bound address, which is
+// all that's needed once the caller only cares about the success/failure code.
+func socks5WriteReply(conn net.Conn, reply byte) error {
+	_, err := conn.Write([]byte{socks5Version, reply, 0x00, socks5AddrIPv4, 0, 0, 0, 0, 0, 0})
+	return err
+}
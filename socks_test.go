@@ -0,0 +1,45 @@
+package sshtun
+
+import (
+	"bufio"
+	"bytes"
+	"testing"
+)
+
+func TestSocks5ReadAddr(t *testing.T) {
+	tests := []struct {
+		name     string
+		addrType byte
+		input    []byte
+		want     string
+		wantErr  bool
+	}{
+		{"IPv4", socks5AddrIPv4, []byte{127, 0, 0, 1}, "127.0.0.1", false},
+		{
+			"IPv6", socks5AddrIPv6,
+			[]byte{0x20, 0x01, 0x0d, 0xb8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1},
+			"2001:db8::1", false,
+		},
+		{"domain", socks5AddrDomain, append([]byte{11}, []byte("example.com")...), "example.com", false},
+		{"unsupported address type", 0x02, nil, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := bufio.NewReader(bytes.NewReader(tt.input))
+			got, err := socks5ReadAddr(r, tt.addrType)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("socks5ReadAddr(%v) = %q, nil; want an error", tt.addrType, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("socks5ReadAddr(%v) unexpected error: %v", tt.addrType, err)
+			}
+			if got != tt.want {
+				t.Errorf("socks5ReadAddr(%v) = %q, want %q", tt.addrType, got, tt.want)
+			}
+		})
+	}
+}
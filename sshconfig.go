@@ -0,0 +1,364 @@
+package sshtun
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sshConfigBlock is one Host/Match block from an OpenSSH client config file (or
+// the implicit top-level block before the first one): the patterns that select
+// it, and its keyword/value pairs in file order.
+type sshConfigBlock struct {
+	patterns []string
+	settings [][2]string
+}
+
+// resolvedSSHConfig holds the settings collected for one alias after walking
+// every block in an OpenSSH client config file that applies to it.
+type resolvedSSHConfig struct {
+	HostName              string
+	Port                  int
+	User                  string
+	IdentityFiles         []string
+	ProxyJump             string
+	UserKnownHostsFile    string
+	StrictHostKeyChecking string
+	ServerAliveInterval   time.Duration
+	Ciphers               []string
+	KEXAlgorithms         []string
+	MACs                  []string
+	LocalForwards         []string
+	RemoteForwards        []string
+	DynamicForwards       []string
+}
+
+// FromSSHConfig resolves hostAlias through `~/.ssh/config` the way the OpenSSH
+// client would, and returns a tunnel configured from the result. See
+// FromSSHConfigFile to use a different config file.
+func FromSSHConfig(hostAlias string) (*SSHTun, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("resolve default ssh config file failed: %w", err)
+	}
+	return FromSSHConfigFile(filepath.Join(home, ".ssh", "config"), hostAlias)
+}
+
+// FromSSHConfigFile resolves hostAlias through the OpenSSH client config file at
+// path, applying HostName, Port, User, IdentityFile, ProxyJump,
+// UserKnownHostsFile, StrictHostKeyChecking, ServerAliveInterval, Ciphers,
+// KEXAlgorithms and MACs, and materializing every LocalForward, RemoteForward
+// and DynamicForward directive as a tunnel spec added with AddTunnel. Callers
+// can still override any of it with SetUser and friends before calling Start.
+func FromSSHConfigFile(path string, hostAlias string) (*SSHTun, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open ssh config file %s failed: %w", path, err)
+	}
+	defer f.Close()
+
+	blocks, err := parseSSHConfig(f)
+	if err != nil {
+		return nil, fmt.Errorf("parse ssh config file %s failed: %w", path, err)
+	}
+
+	resolved := resolveSSHConfig(blocks, hostAlias)
+
+	hostname := resolved.HostName
+	if hostname == "" {
+		hostname = hostAlias
+	}
+
+	sshTun := defaultSSHTun(hostname)
+	sshTun.configHostAlias = hostAlias
+
+	if resolved.Port != 0 {
+		sshTun.SetPort(resolved.Port)
+	}
+	if resolved.User != "" {
+		sshTun.SetUser(resolved.User)
+	}
+	if len(resolved.IdentityFiles) > 0 {
+		sshTun.SetKeyFile(resolved.IdentityFiles[0])
+	}
+	if resolved.UserKnownHostsFile != "" {
+		sshTun.SetKnownHostsFile(resolved.UserKnownHostsFile)
+	}
+	if strings.EqualFold(resolved.StrictHostKeyChecking, "no") {
+		sshTun.SetInsecureIgnoreHostKey()
+	}
+	if len(resolved.Ciphers) > 0 {
+		sshTun.SetCiphers(resolved.Ciphers)
+	}
+	if len(resolved.KEXAlgorithms) > 0 {
+		sshTun.SetKeyExchanges(resolved.KEXAlgorithms)
+	}
+	if len(resolved.MACs) > 0 {
+		sshTun.SetMACs(resolved.MACs)
+	}
+
+	sshTun.proxyJump = resolved.ProxyJump
+	sshTun.serverAliveInterval = resolved.ServerAliveInterval
+	if resolved.ServerAliveInterval > 0 {
+		// Give up after 3 missed probes, OpenSSH's default ServerAliveCountMax.
+		sshTun.SetKeepAlive(resolved.ServerAliveInterval, 3)
+	}
+	if jumps := parseProxyJumpSpec(resolved.ProxyJump); len(jumps) > 0 {
+		sshTun.SetJumpHosts(jumps...)
+	}
+
+	for _, directive := range resolved.LocalForwards {
+		spec, err := parseForwardDirective(Local, directive)
+		if err != nil {
+			return nil, fmt.Errorf("parse LocalForward %q failed: %w", directive, err)
+		}
+		sshTun.AddTunnel(*spec)
+	}
+	for _, directive := range resolved.RemoteForwards {
+		spec, err := parseForwardDirective(Remote, directive)
+		if err != nil {
+			return nil, fmt.Errorf("parse RemoteForward %q failed: %w", directive, err)
+		}
+		sshTun.AddTunnel(*spec)
+	}
+	for _, directive := range resolved.DynamicForwards {
+		sshTun.AddTunnel(TunnelSpec{
+			Direction:  Dynamic,
+			ListenNet:  "tcp",
+			ListenAddr: normalizeDynamicForwardAddr(directive),
+		})
+	}
+
+	return sshTun, nil
+}
+
+// HostAlias returns the alias FromSSHConfig/FromSSHConfigFile resolved this
+// tunnel from, or "" if it wasn't built that way.
+func (tun *SSHTun) HostAlias() string {
+	return tun.configHostAlias
+}
+
+// ProxyJump returns the raw ProxyJump directive resolved from the ssh config
+// file, or "" if none applied.
+func (tun *SSHTun) ProxyJump() string {
+	return tun.proxyJump
+}
+
+// ServerAliveInterval returns the ServerAliveInterval resolved from the ssh
+// config file, or 0 if none applied.
+func (tun *SSHTun) ServerAliveInterval() time.Duration {
+	return tun.serverAliveInterval
+}
+
+// parseSSHConfig reads an OpenSSH client config file into blocks, one per Host
+// or Match keyword plus an implicit "Host *" block for anything before the
+// first one.
+func parseSSHConfig(r io.Reader) ([]sshConfigBlock, error) {
+	blocks := []sshConfigBlock{{patterns: []string{"*"}}}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		keyword, value, err := splitSSHConfigLine(line)
+		if err != nil {
+			return nil, err
+		}
+
+		switch strings.ToLower(keyword) {
+		case "host":
+			blocks = append(blocks, sshConfigBlock{patterns: strings.Fields(value)})
+		case "match":
+			// Only "Match all" is resolved here; the rest of the Match grammar
+			// (exec, canonical, originalhost, ...) needs more context than a
+			// pure config parse has, so any other Match block never applies.
+			if strings.EqualFold(strings.TrimSpace(value), "all") {
+				blocks = append(blocks, sshConfigBlock{patterns: []string{"*"}})
+			} else {
+				blocks = append(blocks, sshConfigBlock{patterns: nil})
+			}
+		default:
+			last := &blocks[len(blocks)-1]
+			last.settings = append(last.settings, [2]string{keyword, value})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return blocks, nil
+}
+
+// splitSSHConfigLine splits a config line into its keyword and value, handling
+// both "Keyword value" and "Keyword=value" forms and trimming surrounding quotes.
+func splitSSHConfigLine(line string) (string, string, error) {
+	idx := strings.IndexAny(line, " \t=")
+	if idx < 0 {
+		return "", "", fmt.Errorf("malformed config line %q", line)
+	}
+
+	keyword := line[:idx]
+	value := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line[idx:]), "="))
+	value = strings.Trim(value, `"`)
+
+	return keyword, value, nil
+}
+
+// matchHostPatterns reports whether alias matches a Host block's space
+// separated patterns, honoring "!pattern" negation the way OpenSSH does: any
+// matching negated pattern rules the block out regardless of the rest.
+func matchHostPatterns(patterns []string, alias string) bool {
+	matched := false
+	for _, p := range patterns {
+		negate := strings.HasPrefix(p, "!")
+		pattern := strings.TrimPrefix(p, "!")
+
+		ok, err := filepath.Match(pattern, alias)
+		if err != nil || !ok {
+			continue
+		}
+		if negate {
+			return false
+		}
+		matched = true
+	}
+
+	return matched
+}
+
+// resolveSSHConfig walks blocks in file order, applying the first value seen
+// for single-valued keywords and accumulating every value seen for multi-valued
+// ones (IdentityFile, the forward directives), the same precedence OpenSSH uses.
+func resolveSSHConfig(blocks []sshConfigBlock, alias string) *resolvedSSHConfig {
+	resolved := &resolvedSSHConfig{}
+
+	for _, block := range blocks {
+		if !matchHostPatterns(block.patterns, alias) {
+			continue
+		}
+
+		for _, kv := range block.settings {
+			keyword, value := strings.ToLower(kv[0]), kv[1]
+			switch keyword {
+			case "hostname":
+				if resolved.HostName == "" {
+					resolved.HostName = value
+				}
+			case "port":
+				if resolved.Port == 0 {
+					if port, err := strconv.Atoi(value); err == nil {
+						resolved.Port = port
+					}
+				}
+			case "user":
+				if resolved.User == "" {
+					resolved.User = value
+				}
+			case "identityfile":
+				resolved.IdentityFiles = append(resolved.IdentityFiles, expandTilde(value))
+			case "proxyjump":
+				if resolved.ProxyJump == "" {
+					resolved.ProxyJump = value
+				}
+			case "userknownhostsfile":
+				if resolved.UserKnownHostsFile == "" {
+					resolved.UserKnownHostsFile = expandTilde(value)
+				}
+			case "stricthostkeychecking":
+				if resolved.StrictHostKeyChecking == "" {
+					resolved.StrictHostKeyChecking = value
+				}
+			case "serveraliveinterval":
+				if resolved.ServerAliveInterval == 0 {
+					if seconds, err := strconv.Atoi(value); err == nil {
+						resolved.ServerAliveInterval = time.Duration(seconds) * time.Second
+					}
+				}
+			case "ciphers":
+				if len(resolved.Ciphers) == 0 {
+					resolved.Ciphers = strings.Split(value, ",")
+				}
+			case "kexalgorithms":
+				if len(resolved.KEXAlgorithms) == 0 {
+					resolved.KEXAlgorithms = strings.Split(value, ",")
+				}
+			case "macs":
+				if len(resolved.MACs) == 0 {
+					resolved.MACs = strings.Split(value, ",")
+				}
+			case "localforward":
+				resolved.LocalForwards = append(resolved.LocalForwards, value)
+			case "remoteforward":
+				resolved.RemoteForwards = append(resolved.RemoteForwards, value)
+			case "dynamicforward":
+				resolved.DynamicForwards = append(resolved.DynamicForwards, value)
+			}
+		}
+	}
+
+	return resolved
+}
+
+func expandTilde(path string) string {
+	if !strings.HasPrefix(path, "~") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~"))
+}
+
+// parseForwardDirective turns a "LocalForward"/"RemoteForward" directive value
+// ("<listen> <forward>") into the equivalent TunnelSpec.
+func parseForwardDirective(direction ForwardType, value string) (*TunnelSpec, error) {
+	fields := strings.Fields(value)
+	if len(fields) != 2 {
+		return nil, fmt.Errorf(`expected "<listen> <forward>", got %q`, value)
+	}
+
+	listenNet, listenAddr := parseForwardEndpoint(fields[0])
+	forwardNet, forwardAddr := parseForwardEndpoint(fields[1])
+
+	return &TunnelSpec{
+		Direction:   direction,
+		ListenNet:   listenNet,
+		ListenAddr:  listenAddr,
+		ForwardNet:  forwardNet,
+		ForwardAddr: forwardAddr,
+	}, nil
+}
+
+// parseForwardEndpoint turns one side of a forward directive into a (network,
+// address) pair: a unix socket path if it starts with "/" or "~", a bare port
+// defaulting its host to localhost (matching ssh's own behavior), or a TCP
+// host:port as-is.
+func parseForwardEndpoint(s string) (string, string) {
+	if strings.HasPrefix(s, "/") || strings.HasPrefix(s, "~") {
+		return "unix", expandTilde(s)
+	}
+	if _, err := strconv.Atoi(s); err == nil {
+		return "tcp", net.JoinHostPort("localhost", s)
+	}
+	return "tcp", s
+}
+
+// normalizeDynamicForwardAddr turns a "DynamicForward" directive value
+// ("[bind_address:]port") into a listen address, defaulting a bare port's host
+// to localhost.
+func normalizeDynamicForwardAddr(value string) string {
+	if _, err := strconv.Atoi(value); err == nil {
+		return net.JoinHostPort("localhost", value)
+	}
+	return value
+}
@@ -0,0 +1,114 @@
+package sshtun
+
+import "testing"
+
+func TestMatchHostPatterns(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		alias    string
+		want     bool
+	}{
+		{"wildcard matches anything", []string{"*"}, "box1", true},
+		{"exact match", []string{"box1"}, "box1", true},
+		{"no match", []string{"box1"}, "box2", false},
+		{"glob match", []string{"box*"}, "box2", true},
+		{"negation rules out an otherwise matching pattern", []string{"*", "!box2"}, "box2", false},
+		{"negation leaves other aliases matching", []string{"*", "!box2"}, "box1", true},
+		{"empty patterns never match", nil, "box1", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchHostPatterns(tt.patterns, tt.alias); got != tt.want {
+				t.Errorf("matchHostPatterns(%v, %q) = %v, want %v", tt.patterns, tt.alias, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveSSHConfig(t *testing.T) {
+	blocks := []sshConfigBlock{
+		{
+			// Specific Host blocks precede the catch-all, same as a real
+			// ssh_config file must for their settings to actually win.
+			patterns: []string{"box1"},
+			settings: [][2]string{
+				{"hostname", "box1.internal"},
+				{"user", "alice"},
+				{"identityfile", "~/.ssh/id_box1"},
+				{"localforward", "8080 localhost:80"},
+			},
+		},
+		{
+			patterns: []string{"box1"},
+			settings: [][2]string{
+				// A second matching block's user must not override the first.
+				{"user", "bob"},
+				{"identityfile", "~/.ssh/id_box1_fallback"},
+			},
+		},
+		{
+			patterns: []string{"*"},
+			settings: [][2]string{
+				{"user", "default-user"},
+				{"port", "22"},
+			},
+		},
+		{
+			patterns: []string{"box2"},
+			settings: [][2]string{
+				{"hostname", "box2.internal"},
+			},
+		},
+	}
+
+	resolved := resolveSSHConfig(blocks, "box1")
+
+	if resolved.HostName != "box1.internal" {
+		t.Errorf("HostName = %q, want box1.internal", resolved.HostName)
+	}
+	if resolved.User != "alice" {
+		t.Errorf("User = %q, want alice (first match wins, and the specific Host block precedes the catch-all)", resolved.User)
+	}
+	if resolved.Port != 22 {
+		t.Errorf("Port = %d, want 22 (from the catch-all block, since box1 doesn't set one)", resolved.Port)
+	}
+	if len(resolved.IdentityFiles) != 2 {
+		t.Fatalf("IdentityFiles = %v, want 2 entries (multi-valued keywords accumulate)", resolved.IdentityFiles)
+	}
+	if len(resolved.LocalForwards) != 1 || resolved.LocalForwards[0] != "8080 localhost:80" {
+		t.Errorf("LocalForwards = %v, want [\"8080 localhost:80\"]", resolved.LocalForwards)
+	}
+
+	resolvedOther := resolveSSHConfig(blocks, "box2")
+	if resolvedOther.HostName != "box2.internal" {
+		t.Errorf("HostName = %q, want box2.internal", resolvedOther.HostName)
+	}
+	if resolvedOther.User != "default-user" {
+		t.Errorf("User = %q, want default-user from the catch-all Host * block", resolvedOther.User)
+	}
+}
+
+func TestParseForwardEndpoint(t *testing.T) {
+	tests := []struct {
+		in       string
+		wantNet  string
+		wantAddr string
+	}{
+		{"8080", "tcp", "localhost:8080"},
+		{"localhost:8080", "tcp", "localhost:8080"},
+		{"/tmp/sock", "unix", "/tmp/sock"},
+		{"~/sock", "unix", expandTilde("~/sock")},
+	}
+
+	for _, tt := range tests {
+		net, addr := parseForwardEndpoint(tt.in)
+		if net != tt.wantNet {
+			t.Errorf("parseForwardEndpoint(%q) net = %q, want %q", tt.in, net, tt.wantNet)
+		}
+		if addr != tt.wantAddr {
+			t.Errorf("parseForwardEndpoint(%q) addr = %q, want %q", tt.in, addr, tt.wantAddr)
+		}
+	}
+}
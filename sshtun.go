@@ -38,17 +38,38 @@ type SSHTun struct {
 	sshConfigCiphers      []string
 	sshConfigMACs         []string
 
+	tunnels []*TunnelSpec
+
+	knownHostsFile        string
+	knownHostsTOFU        bool
+	insecureIgnoreHostKey bool
+	hostKeyCallback       ssh.HostKeyCallback
+	hostKeyAlgorithms     []string
+
+	configHostAlias     string
+	proxyJump           string
+	serverAliveInterval time.Duration
+
+	jumpHosts []*JumpHost
+
+	keepAliveInterval  time.Duration
+	keepAliveMaxMisses int
+	keepAliveErr       error
+	reconnectPolicy    *ReconnectPolicy
+
 	name string
 }
 
 // ForwardType is the type of port forwarding.
 // Local: forward from localhost.
 // Remote: forward from remote - reverse port forward.
+// Dynamic: turn the local endpoint into a SOCKS5 proxy tunneled over the SSH connection.
 type ForwardType int
 
 const (
 	Local ForwardType = iota
 	Remote
+	Dynamic
 )
 
 // ConnState represents the state of the SSH tunnel. It's returned to an optional function provided to SetConnState.
@@ -65,6 +86,11 @@ const (
 	// StateStarted represents a tunnel ready to accept connections.
 	// A call to stop or an error will make the state to transition to StateStopped.
 	StateStarted
+
+	// StateReconnecting represents a tunnel backing off between automatic
+	// restarts under Run, after Start returned because it stopped or a
+	// keepalive timed out. The next restart attempt transitions back to StateStarting.
+	StateReconnecting
 )
 
 // New creates a new SSH tunnel to the specified server redirecting a port on local localhost to a port on remote localhost.
@@ -242,6 +268,7 @@ func (tun *SSHTun) Start(ctx context.Context) error {
 	}
 	tun.started = true
 	tun.ctx, tun.cancel = context.WithCancel(ctx)
+	tun.keepAliveErr = nil
 	tun.mutex.Unlock()
 
 	if tun.connState != nil {
@@ -254,6 +281,18 @@ func (tun *SSHTun) Start(ctx context.Context) error {
 	}
 	tun.sshConfig = config
 
+	if len(tun.tunnels) == 0 && tun.forwardType == Dynamic {
+		// A Dynamic forward type set with SetForwardType runs as a single
+		// Dynamic spec listening on the local endpoint.
+		tun.mutex.Lock()
+		tun.tunnels = []*TunnelSpec{{Direction: Dynamic, ListenNet: tun.local.Type(), ListenAddr: tun.local.String()}}
+		tun.mutex.Unlock()
+	}
+
+	if len(tun.tunnels) > 0 {
+		return tun.startTunnels()
+	}
+
 	listenConfig := net.ListenConfig{}
 	var listener net.Listener
 
@@ -263,10 +302,11 @@ func (tun *SSHTun) Start(ctx context.Context) error {
 			return tun.stop(fmt.Errorf("local listen %s on %s failed: %w", tun.local.Type(), tun.local.String(), err))
 		}
 	} else if tun.forwardType == Remote {
-		sshClient, err := ssh.Dial(tun.server.Type(), tun.server.String(), tun.sshConfig)
+		sshClient, err := tun.dialServer()
 		if err != nil {
-			return tun.stop(fmt.Errorf("ssh dial %s to %s failed: %w", tun.server.Type(), tun.server.String(), err))
+			return tun.stop(err)
 		}
+		tun.startKeepAlive(sshClient)
 		listener, err = sshClient.Listen(tun.remote.Type(), tun.remote.String())
 		if err != nil {
 			return tun.stop(fmt.Errorf("remote listen %s on %s failed: %w", tun.remote.Type(), tun.remote.String(), err))
@@ -296,17 +336,21 @@ func (tun *SSHTun) Stop() {
 }
 
 func (tun *SSHTun) initSSHConfig() (*ssh.ClientConfig, error) {
+	hostKeyCallback, err := tun.buildHostKeyCallback()
+	if err != nil {
+		return nil, fmt.Errorf("host key verification setup failed: %w", err)
+	}
+
 	config := &ssh.ClientConfig{
 		Config: ssh.Config{
 			KeyExchanges: tun.sshConfigKeyExchanges,
 			Ciphers:      tun.sshConfigCiphers,
 			MACs:         tun.sshConfigMACs,
 		},
-		User: tun.user,
-		HostKeyCallback: func(hostname string, remote net.Addr, key ssh.PublicKey) error {
-			return nil
-		},
-		Timeout: tun.timeout,
+		User:              tun.user,
+		HostKeyCallback:   hostKeyCallback,
+		HostKeyAlgorithms: tun.hostKeyAlgorithms,
+		Timeout:           tun.timeout,
 	}
 
 	authMethod, err := tun.getSSHAuthMethod()
@@ -383,13 +427,22 @@ func (tun *SSHTun) listen(listener net.Listener) error {
 
 	err := errGroup.Wait()
 
+	return tun.stoppedErr(err)
+}
+
+// stoppedErr decides what a listen loop should return once tun.ctx is done:
+// the keepalive timeout's error if that's what cancelled it (so Start/Run
+// report a real error instead of looking like a clean Stop), nil if Stop or
+// the caller's ctx did, or err unchanged if tun.ctx isn't done at all.
+func (tun *SSHTun) stoppedErr(err error) error {
 	select {
 	case <-tun.ctx.Done():
+		tun.mutex.Lock()
+		defer tun.mutex.Unlock()
+		return tun.keepAliveErr
 	default:
 		return err
 	}
-
-	return nil
 }
 
 func (tun *SSHTun) handle(conn net.Conn) error {
@@ -409,11 +462,12 @@ func (tun *SSHTun) addConn() error {
 	defer tun.mutex.Unlock()
 
 	if tun.forwardType == Local && tun.active == 0 {
-		sshClient, err := ssh.Dial(tun.server.Type(), tun.server.String(), tun.sshConfig)
+		sshClient, err := tun.dialServer()
 		if err != nil {
-			return fmt.Errorf("ssh dial %s to %s failed: %w", tun.server.Type(), tun.server.String(), err)
+			return err
 		}
 		tun.sshClient = sshClient
+		tun.startKeepAlive(sshClient)
 	}
 
 	tun.active += 1
@@ -0,0 +1,310 @@
+package sshtun
+
+import (
+	"fmt"
+	"io"
+	"net"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// TunnelSpec describes a single forward multiplexed over a tunnel's shared SSH
+// connection. Direction selects Local, Remote or Dynamic, same as SSHTun.forwardType.
+// ListenNet/ListenAddr is the side that accepts connections ("tcp" or "unix",
+// using the address forms net.Listen understands); ForwardNet/ForwardAddr is the
+// side connections get bridged to. Dynamic specs ignore ForwardNet/ForwardAddr:
+// each connection's destination comes from its SOCKS5 request.
+type TunnelSpec struct {
+	Direction   ForwardType
+	ListenNet   string
+	ListenAddr  string
+	ForwardNet  string
+	ForwardAddr string
+}
+
+// AddTunnel appends a forward to the list of specs carried by this tunnel's SSH
+// connection. It has no effect once the tunnel has started; call it before Start.
+// Once one or more specs have been added this way, they take over from the
+// legacy single local/remote forward configured through New and friends.
+func (tun *SSHTun) AddTunnel(spec TunnelSpec) {
+	tun.mutex.Lock()
+	defer tun.mutex.Unlock()
+
+	tun.tunnels = append(tun.tunnels, &spec)
+}
+
+// SetTunnels replaces the list of forwards carried by this tunnel's SSH
+// connection, letting a single authenticated session serve many Local and
+// Remote forwards concurrently, TCP and unix sockets mixed freely.
+func (tun *SSHTun) SetTunnels(specs []TunnelSpec) {
+	tun.mutex.Lock()
+	defer tun.mutex.Unlock()
+
+	tun.tunnels = make([]*TunnelSpec, len(specs))
+	for i := range specs {
+		spec := specs[i]
+		tun.tunnels[i] = &spec
+	}
+}
+
+// tunnelListener pairs a spec with the listener started for it, so accepted
+// connections can be traced back to the spec they belong to.
+type tunnelListener struct {
+	spec     *TunnelSpec
+	listener net.Listener
+}
+
+// startTunnels dials the server once and brings up every configured spec on top
+// of the resulting client: Remote specs via sshClient.Listen, Local specs via
+// net.ListenConfig. All listeners share tun.sshClient for the lifetime of Start.
+func (tun *SSHTun) startTunnels() error {
+	sshClient, err := tun.dialServer()
+	if err != nil {
+		return tun.stop(err)
+	}
+
+	tun.mutex.Lock()
+	tun.sshClient = sshClient
+	tun.mutex.Unlock()
+	tun.startKeepAlive(sshClient)
+
+	listenConfig := net.ListenConfig{}
+	listeners := make([]*tunnelListener, 0, len(tun.tunnels))
+
+	for _, spec := range tun.tunnels {
+		var listener net.Listener
+		if spec.Direction == Remote {
+			listener, err = sshClient.Listen(spec.ListenNet, spec.ListenAddr)
+		} else {
+			listener, err = listenConfig.Listen(tun.ctx, spec.ListenNet, spec.ListenAddr)
+		}
+		if err != nil {
+			for _, l := range listeners {
+				l.listener.Close()
+			}
+			sshClient.Close()
+			tun.mutex.Lock()
+			tun.sshClient = nil
+			tun.mutex.Unlock()
+			return tun.stop(fmt.Errorf("listen %s on %s failed: %w", spec.ListenNet, spec.ListenAddr, err))
+		}
+		listeners = append(listeners, &tunnelListener{spec: spec, listener: listener})
+	}
+
+	errChan := make(chan error)
+	go func() {
+		errChan <- tun.listenTunnels(listeners)
+	}()
+
+	if tun.connState != nil {
+		tun.connState(tun, StateStarted)
+	}
+
+	return tun.stop(<-errChan)
+}
+
+func (tun *SSHTun) listenTunnels(listeners []*tunnelListener) error {
+	errGroup, groupCtx := errgroup.WithContext(tun.ctx)
+
+	for _, tl := range listeners {
+		tl := tl
+		errGroup.Go(func() error {
+			for {
+				conn, err := tl.listener.Accept()
+				if err != nil {
+					return fmt.Errorf("accept %s on %s failed: %w", tl.spec.ListenNet, tl.spec.ListenAddr, err)
+				}
+				errGroup.Go(func() error {
+					return tun.handleSpec(tl.spec, conn)
+				})
+			}
+		})
+	}
+
+	<-groupCtx.Done()
+
+	for _, tl := range listeners {
+		tl.listener.Close()
+	}
+
+	err := errGroup.Wait()
+
+	tun.mutex.Lock()
+	if tun.sshClient != nil {
+		tun.sshClient.Close()
+		tun.sshClient = nil
+	}
+	tun.mutex.Unlock()
+
+	return tun.stoppedErr(err)
+}
+
+func (tun *SSHTun) handleSpec(spec *TunnelSpec, conn net.Conn) error {
+	tun.addSpecConn()
+
+	err := tun.forwardSpec(spec, conn)
+
+	tun.removeSpecConn()
+
+	return err
+}
+
+// forwardSpec dials the other side of spec and bridges conn with it: out through
+// the shared sshClient for Local specs, out over plain net.Dial for Remote specs
+// whose listener already lives on the server, or via a per-connection SOCKS5
+// handshake for Dynamic specs.
+func (tun *SSHTun) forwardSpec(spec *TunnelSpec, conn net.Conn) error {
+	defer conn.Close()
+
+	if spec.Direction == Dynamic {
+		return tun.forwardDynamicSpec(spec, conn)
+	}
+
+	var (
+		target net.Conn
+		err    error
+	)
+	if spec.Direction == Remote {
+		target, err = net.Dial(spec.ForwardNet, spec.ForwardAddr)
+	} else {
+		tun.mutex.Lock()
+		sshClient := tun.sshClient
+		tun.mutex.Unlock()
+		target, err = sshClient.Dial(spec.ForwardNet, spec.ForwardAddr)
+	}
+	if err != nil {
+		return fmt.Errorf("dial %s on %s failed: %w", spec.ForwardNet, spec.ForwardAddr, err)
+	}
+	defer target.Close()
+
+	tun.bridge(spec, "", conn, target)
+
+	return nil
+}
+
+// forwardDynamicSpec performs the SOCKS5 handshake on conn and bridges it with
+// whatever destination it requested, dialed through the tunnel's shared client.
+func (tun *SSHTun) forwardDynamicSpec(spec *TunnelSpec, conn net.Conn) error {
+	targetAddr, err := socks5Handshake(conn)
+	if err != nil {
+		return fmt.Errorf("socks5 handshake failed: %w", err)
+	}
+
+	tun.mutex.Lock()
+	sshClient := tun.sshClient
+	tun.mutex.Unlock()
+
+	target, err := sshClient.Dial("tcp", targetAddr)
+	if err != nil {
+		socks5WriteReply(conn, socks5ReplyHostUnreachable)
+		return fmt.Errorf("dial %s through tunnel failed: %w", targetAddr, err)
+	}
+	defer target.Close()
+
+	if err := socks5WriteReply(conn, socks5ReplySuccess); err != nil {
+		return fmt.Errorf("socks5 reply to %s failed: %w", targetAddr, err)
+	}
+
+	tun.bridge(spec, targetAddr, conn, target)
+
+	return nil
+}
+
+// addSpecConn reference-counts an active connection against the tunnel's shared
+// client. Unlike the single-forward addConn, it never dials: Start has already
+// established tun.sshClient before any listener accepts a connection.
+func (tun *SSHTun) addSpecConn() {
+	tun.mutex.Lock()
+	tun.active += 1
+	tun.mutex.Unlock()
+}
+
+func (tun *SSHTun) removeSpecConn() {
+	tun.mutex.Lock()
+	tun.active -= 1
+	tun.mutex.Unlock()
+}
+
+// TunneledConnState represents the state of a single tunneled connection. It is
+// reported through the optional callback function provided to SetTunneledConnState.
+type TunneledConnState struct {
+	// Spec is the forward this connection belongs to, for tunnels configured
+	// through AddTunnel/SetTunnels.
+	Spec *TunnelSpec
+	// Target is the resolved "host:port" destination for Dynamic (SOCKS5)
+	// forwards, empty for every other forward type.
+	Target string
+	// Conn is the side of the connection accepted by the tunnel's listener.
+	Conn net.Conn
+	// Closed reports whether the connection has finished being tunneled.
+	Closed bool
+	// BytesIn is the number of bytes copied from the forwarded side into Conn.
+	// It's only final once Closed is true.
+	BytesIn int64
+	// BytesOut is the number of bytes copied from Conn into the forwarded side.
+	// It's only final once Closed is true.
+	BytesOut int64
+	// Error holds the error that ended the connection, if any.
+	Error error
+}
+
+func (tun *SSHTun) reportSpecConnState(spec *TunnelSpec, targetAddr string, conn net.Conn, closed bool, bytesIn, bytesOut int64, err error) {
+	if tun.tunneledConnState == nil {
+		return
+	}
+	tun.tunneledConnState(tun, &TunneledConnState{
+		Spec:     spec,
+		Target:   targetAddr,
+		Conn:     conn,
+		Closed:   closed,
+		BytesIn:  bytesIn,
+		BytesOut: bytesOut,
+		Error:    err,
+	})
+}
+
+// copyResult is one direction's outcome from bridge: how many bytes it moved
+// before returning, and the error (often just EOF) that ended it.
+type copyResult struct {
+	bytes int64
+	err   error
+}
+
+// bridge copies bytes in both directions between conn and target until one
+// side stops (closing the other to unblock it too), reporting the
+// connection's state and final byte counts before and after through
+// reportSpecConnState. targetAddr is only meaningful for Dynamic specs, where
+// it carries the SOCKS5-resolved destination; it's empty otherwise. The
+// reported error is whichever side stopped first; the other side's error is
+// just the forced Close unblocking it and carries no information.
+func (tun *SSHTun) bridge(spec *TunnelSpec, targetAddr string, conn, target net.Conn) {
+	tun.reportSpecConnState(spec, targetAddr, conn, false, 0, 0, nil)
+
+	outChan := make(chan copyResult, 1)
+	inChan := make(chan copyResult, 1)
+	go func() {
+		n, err := io.Copy(target, conn)
+		outChan <- copyResult{bytes: n, err: err}
+	}()
+	go func() {
+		n, err := io.Copy(conn, target)
+		inChan <- copyResult{bytes: n, err: err}
+	}()
+
+	var out, in copyResult
+	var err error
+	select {
+	case out = <-outChan:
+		err = out.err
+		conn.Close()
+		target.Close()
+		in = <-inChan
+	case in = <-inChan:
+		err = in.err
+		conn.Close()
+		target.Close()
+		out = <-outChan
+	}
+
+	tun.reportSpecConnState(spec, targetAddr, conn, true, in.bytes, out.bytes, err)
+}